@@ -4,180 +4,234 @@ Inquirer sets up a server that listens for POST data (on specified port) and sen
 package inquirer
 
 import (
+	"context"
+	"errors"
 	"log"
-	"net"
 	"net/http"
-	"os"
-	"regexp"
-	"strings"
+	"time"
 
-	"github.com/presence-web-services/gmailer/v2"
-	"github.com/joho/godotenv"
+	"github.com/presence-web-services/reviewer/internal/app"
+	"github.com/presence-web-services/reviewer/internal/mail"
+	"github.com/presence-web-services/reviewer/internal/store"
+	"github.com/presence-web-services/reviewer/internal/tenant"
 )
 
-// gmailer config for sending email
-var config gmailer.Config
+// errNotAllowedRecipient is returned by retrySubmission when a submission's
+// tenant's EmailTo is not in its AllowedRecipients, so the retry worker
+// records the submission as failed and backs off instead of sending it.
+var errNotAllowedRecipient = errors.New("recipient not in tenant's allowed_recipients")
 
-// default important values
-var status = http.StatusOK
-var errorMessage = ""
-var hp = ""
-var site = ""
+// a holds the mailer, tenant registry, anti-spam/email-verification config
+// and submission store shared across requests.
+var a = app.New()
 
-// init loads environment variables and authenticates the gmailer config
+// init loads environment variables and authenticates the default mailer
 func init() {
-	loadEnvVars()
+	a.LoadEnvVars()
 	authenticate()
 }
 
-// CreateAndRun is exported to allow for creation of an inquirer
-func CreateAndRun(port string) {
+// CreateAndRun is exported to allow for creation of an inquirer. If m is
+// non-nil, it replaces the mailer built from the environment.
+func CreateAndRun(port string, m mail.Mailer) {
+	if m != nil {
+		a.Mailer = m
+	}
 	http.HandleFunc("/", handler)
+	http.HandleFunc("/submissions", a.SubmissionsHandler)
 	http.ListenAndServe(":"+port, nil)
 }
 
-// loadEnvVars loads environment variables from a .env file
-func loadEnvVars() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error: Could not load environment variables from .env file.")
-	}
-	config.ClientID = os.Getenv("CLIENT_ID")
-	config.ClientSecret = os.Getenv("CLIENT_SECRET")
-	config.AccessToken = os.Getenv("ACCESS_TOKEN")
-	config.RefreshToken = os.Getenv("REFRESH_TOKEN")
-	config.EmailTo = os.Getenv("EMAIL_TO")
-	config.EmailFrom = os.Getenv("EMAIL_FROM")
-	config.Subject = os.Getenv("SUBJECT")
-	site = "https://" + os.Getenv("SITE")
-}
-
-// authenticate authenticates a gmailer config
+// authenticate authenticates the default mailer
 func authenticate() {
-	err := config.Authenticate()
-	if err != nil {
-		log.Fatal("Error: Could not authenticate with GMail OAuth using credentials.")
+	if err := a.Authenticate(); err != nil {
+		log.Println("Warning: Could not authenticate mailer using configured credentials.")
 	}
 }
 
-// sendEmail sends an email given a gmailer config
-func sendEmail() {
-	err := config.Send()
-	if err != nil {
-		status = http.StatusInternalServerError
-		errorMessage = "Error: Internal server error."
-		return
-	}
+// submission holds all state for a single in-flight POST. Every handler
+// invocation gets its own submission, so concurrent requests can never
+// clobber each other's fields.
+type submission struct {
+	status  int
+	errMsg  string
+	tenant  tenant.Config
+	replyTo string
+	body    string
+	hp      string
+
+	// storeID is the persisted row ID for this submission, or 0 if it was
+	// never saved (no Store configured, or the save itself failed).
+	storeID int64
 }
 
-// defaultValues sets the status, errorMessage, ReplyTo, Body all to default values
-func defaultValues() {
-	status = http.StatusOK
-	errorMessage = ""
-	hp = ""
-	config.ReplyTo = ""
-	config.Body = ""
+// newSubmission returns a submission primed with the default status.
+func newSubmission() *submission {
+	return &submission{status: http.StatusOK}
 }
 
 // handler verifies a POST is sent, and then validates the POST data, and sends an email if valid
 func handler(response http.ResponseWriter, request *http.Request) {
-	defaultValues()
-	response.Header().Set("Access-Control-Allow-Origin", site)
-	checkOrigin(request)
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s := newSubmission()
+	s.resolveTenant(request)
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
+		return
+	}
+	response.Header().Set("Access-Control-Allow-Origin", s.tenant.AllowedOrigin)
+	s.verifyPost(response, request.Method)
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	verifyPost(response, request.Method)
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.getFormData(request)
+	s.checkSpam(request)
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	getFormData(request)
-	checkEmail()
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.checkEmail()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	checkMessage()
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.checkMessage()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	checkHP()
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.checkHP()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	sendEmail()
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.saveSubmission(request)
+	s.sendEmail()
+	s.recordEmailOutcome()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
 	response.Write([]byte("Email sent successfully!"))
 }
 
-// checkOrigin ensures origin is from proper website
-func checkOrigin(request *http.Request) {
-	origin := request.Header.Get("Origin")
-	if origin != site {
-		status = http.StatusForbidden
-		errorMessage = "Error: Only certain sites are allowed to use this endpoint."
+// resolveTenant looks up the tenant for request, so one deployment can
+// serve several sites.
+func (s *submission) resolveTenant(request *http.Request) {
+	t, ok := a.ResolveTenant(request)
+	if !ok {
+		s.status = http.StatusForbidden
+		s.errMsg = "Error: Only certain sites are allowed to use this endpoint."
 		return
 	}
+	s.tenant = t
 }
 
 // verifyPost ensures that a POST is sent
-func verifyPost(response http.ResponseWriter, method string) {
-	if method != "POST" {
-		response.Header().Set("Allow", "POST")
-		status = http.StatusMethodNotAllowed
-		errorMessage = "Error: Method " + method + " not allowed. Only POST allowed."
+func (s *submission) verifyPost(response http.ResponseWriter, method string) {
+	if status, errMsg := app.VerifyPost(response, method); status != 0 {
+		s.status, s.errMsg = status, errMsg
 	}
 }
 
-// getFormData populates config struct and hp variable with POSTed data from form submission
-func getFormData(request *http.Request) {
-	config.ReplyTo = request.PostFormValue("email")
-	config.Body = request.PostFormValue("message")
-	hp = request.PostFormValue("hp")
+// getFormData populates the submission with POSTed data from the form
+func (s *submission) getFormData(request *http.Request) {
+	s.replyTo = request.PostFormValue("email")
+	s.body = request.PostFormValue("message")
+	s.hp = request.PostFormValue(s.tenant.HoneypotFieldName)
 }
 
 // checkEmail verifies email submitted is valid
-func checkEmail() {
-	if len(config.ReplyTo) < 5 || len(config.ReplyTo) > 50 {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Email is too short or too long."
-		return
+func (s *submission) checkEmail() {
+	if status, errMsg := a.CheckEmail(s.replyTo); status != 0 {
+		s.status, s.errMsg = status, errMsg
 	}
-	var emailRegex = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
-	if !emailRegex.MatchString(config.ReplyTo) {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Email is not a valid format."
-		return
-	}
-	domain := strings.Split(config.ReplyTo, "@")[1]
-	mx, err := net.LookupMX(domain)
-	if err != nil || len(mx) == 0 {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Domain given is not a valid email domain."
-		return
+}
+
+// checkSpam runs the tenant's anti-spam pipeline, if enabled: per-IP and
+// per-email rate limiting, CAPTCHA verification, then content heuristics.
+func (s *submission) checkSpam(request *http.Request) {
+	if status, errMsg := a.CheckSpam(request, s.tenant, s.replyTo, s.body); status != 0 {
+		s.status, s.errMsg = status, errMsg
 	}
 }
 
 // checkMessage verifies message submitted is valid
-func checkMessage() {
-	if len(config.Body) == 0 || len(config.Body) > 2000 {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Message is too long or empty."
+func (s *submission) checkMessage() {
+	if len(s.body) == 0 || len(s.body) > 2000 {
+		s.status = http.StatusBadRequest
+		s.errMsg = "Error: Message is too long or empty."
 		return
 	}
 }
 
 // checkHP ensures honeypot field is not populated
-func checkHP() {
-	if hp != "" {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Please, no robots!"
+func (s *submission) checkHP() {
+	if status, errMsg := app.CheckHP(s.hp); status != 0 {
+		s.status, s.errMsg = status, errMsg
+	}
+}
+
+// sendEmail sends the submission through the configured mailer, routed to
+// this submission's tenant
+func (s *submission) sendEmail() {
+	if !s.tenant.RecipientAllowed(s.tenant.EmailTo) {
+		s.status = http.StatusInternalServerError
+		s.errMsg = "Error: Internal server error."
+		return
+	}
+	err := a.Mailer.Send(context.Background(), mail.Message{
+		ReplyTo: s.replyTo,
+		Body:    s.body,
+		To:      s.tenant.EmailTo,
+		From:    s.tenant.EmailFrom,
+		Subject: s.tenant.Subject,
+	})
+	if err != nil {
+		s.status = http.StatusInternalServerError
+		s.errMsg = "Error: Internal server error."
+		return
 	}
 }
+
+// saveSubmission persists the submission before sendEmail is attempted, so
+// a failed send does not lose it. It is a no-op if no Store is configured.
+func (s *submission) saveSubmission(request *http.Request) {
+	sub := &store.Submission{
+		Tenant:      s.tenant.ID,
+		ReceivedAt:  time.Now(),
+		RemoteIP:    app.ClientIP(request),
+		Email:       s.replyTo,
+		Message:     s.body,
+		EmailStatus: store.StatusPending,
+	}
+	s.storeID = a.SaveSubmission(sub)
+}
+
+// recordEmailOutcome updates the persisted submission, if any, with the
+// result of sendEmail.
+func (s *submission) recordEmailOutcome() {
+	a.RecordEmailOutcome(s.storeID, s.status, s.errMsg)
+}
+
+// StartRetryWorker periodically resends submissions whose email failed,
+// backing off exponentially per submission so a down mail server isn't
+// hammered. It returns a function that stops the worker.
+func StartRetryWorker(interval time.Duration) (stop func()) {
+	return a.StartRetryWorker(interval, retrySubmission)
+}
+
+// retrySubmission re-sends a single persisted submission, addressed and
+// worded the same way a fresh submission would be.
+func retrySubmission(ctx context.Context, sub store.Submission, t tenant.Config) error {
+	if !t.RecipientAllowed(t.EmailTo) {
+		return errNotAllowedRecipient
+	}
+	return a.Mailer.Send(ctx, mail.Message{
+		ReplyTo: sub.Email,
+		Body:    sub.Message,
+		To:      t.EmailTo,
+		From:    t.EmailFrom,
+		Subject: t.Subject,
+	})
+}
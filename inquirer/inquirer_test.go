@@ -0,0 +1,70 @@
+package inquirer
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestConcurrentSubmissionsDoNotCrossTalk runs many submissions with
+// distinct payloads through the validation pipeline in parallel and
+// checks that each one only ever sees its own data.
+func TestConcurrentSubmissionsDoNotCrossTalk(t *testing.T) {
+	cases := []struct {
+		replyTo string
+		body    string
+	}{
+		{"alice@example.com", "Hi, I have a question about pricing."},
+		{"bob@example.com", "Can you build me a website?"},
+		{"carol@example.com", "What are your office hours?"},
+		{"dave@example.com", "I'd like a quote for a redesign."},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for _, c := range cases {
+			c := c
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				s := newSubmission()
+				s.replyTo = c.replyTo
+				s.body = c.body
+
+				s.checkMessage()
+				s.checkHP()
+
+				if s.status != 200 {
+					t.Errorf("unexpected status %d for %s", s.status, c.replyTo)
+				}
+				if s.body != c.body {
+					t.Errorf("cross-talk detected: got body %q, want %q", s.body, c.body)
+				}
+				if s.replyTo != c.replyTo {
+					t.Errorf("cross-talk detected: got replyTo %q, want %q", s.replyTo, c.replyTo)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestCheckMessage(t *testing.T) {
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{"", true},
+		{"Hello there", false},
+		{string(make([]byte, 2001)), true},
+	}
+	for _, tt := range tests {
+		s := newSubmission()
+		s.body = tt.body
+		s.checkMessage()
+		gotErr := s.status != 200
+		if gotErr != tt.wantErr {
+			t.Errorf("checkMessage(len=%d) error = %v, want %v", len(tt.body), gotErr, tt.wantErr)
+		}
+	}
+}
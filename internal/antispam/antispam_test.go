@@ -0,0 +1,85 @@
+package antispam
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketLimiterAllowsBurstThenThrottles(t *testing.T) {
+	l := NewTokenBucketLimiter(0, 2)
+	if !l.Allow("a") {
+		t.Error("first request should be allowed")
+	}
+	if !l.Allow("a") {
+		t.Error("second request (within burst) should be allowed")
+	}
+	if l.Allow("a") {
+		t.Error("third request should be throttled")
+	}
+	if !l.Allow("b") {
+		t.Error("a different key should have its own budget")
+	}
+}
+
+// TestTokenBucketLimiterSweepEvictsIdleBuckets guards against unbounded
+// memory growth when a caller rate-limits on an attacker-influenced key
+// (e.g. an unvalidated submitted email address): a bucket idle past
+// bucketTTL must be evicted by sweep.
+func TestTokenBucketLimiterSweepEvictsIdleBuckets(t *testing.T) {
+	l := NewTokenBucketLimiter(1, 2)
+	l.Allow("a")
+	l.Allow("b")
+	if len(l.buckets) != 2 {
+		t.Fatalf("buckets = %d, want 2", len(l.buckets))
+	}
+
+	l.sweep(time.Now().Add(bucketTTL + time.Minute))
+	if len(l.buckets) != 0 {
+		t.Errorf("buckets after sweep = %d, want 0", len(l.buckets))
+	}
+}
+
+func TestContentScoreFlagsLinksAndPhrases(t *testing.T) {
+	plain := ContentScore("Thanks for the great service!", nil)
+	if plain != 0 {
+		t.Errorf("ContentScore(plain) = %d, want 0", plain)
+	}
+
+	spammy := ContentScore("Click http://spam.example and http://more.example BUY NOW CHEAP", []string{"buy now"})
+	if spammy <= plain {
+		t.Errorf("ContentScore(spammy) = %d, want > %d", spammy, plain)
+	}
+}
+
+func TestLimiterFromEnvDefaultsToInMemory(t *testing.T) {
+	t.Setenv("REDIS_ADDR", "")
+	l := LimiterFromEnv(1, 5)
+	if _, ok := l.(*TokenBucketLimiter); !ok {
+		t.Errorf("LimiterFromEnv() with no REDIS_ADDR = %T, want *TokenBucketLimiter", l)
+	}
+}
+
+func TestLimiterFromEnvUsesRedisWhenConfigured(t *testing.T) {
+	t.Setenv("REDIS_ADDR", "localhost:6379")
+	l := LimiterFromEnv(1, 5)
+	if _, ok := l.(*RedisLimiter); !ok {
+		t.Errorf("LimiterFromEnv() with REDIS_ADDR set = %T, want *RedisLimiter", l)
+	}
+}
+
+func TestVerifyCaptchaNoneAlwaysPasses(t *testing.T) {
+	ok, err := VerifyCaptcha(context.Background(), CaptchaNone, "", "", "")
+	if err != nil || !ok {
+		t.Errorf("VerifyCaptcha(CaptchaNone) = %v, %v; want true, nil", ok, err)
+	}
+}
+
+func TestResponseField(t *testing.T) {
+	if got := ResponseField(CaptchaHCaptcha); got != "h-captcha-response" {
+		t.Errorf("ResponseField(hcaptcha) = %q", got)
+	}
+	if got := ResponseField(CaptchaTurnstile); got != "cf-turnstile-response" {
+		t.Errorf("ResponseField(turnstile) = %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package antispam
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CaptchaProvider selects which CAPTCHA service to verify a response
+// against.
+type CaptchaProvider string
+
+// Supported CAPTCHA providers. CaptchaNone disables verification.
+const (
+	CaptchaNone      CaptchaProvider = ""
+	CaptchaHCaptcha  CaptchaProvider = "hcaptcha"
+	CaptchaTurnstile CaptchaProvider = "turnstile"
+)
+
+var verifyURLs = map[CaptchaProvider]string{
+	CaptchaHCaptcha:  "https://hcaptcha.com/siteverify",
+	CaptchaTurnstile: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+
+var responseFields = map[CaptchaProvider]string{
+	CaptchaHCaptcha:  "h-captcha-response",
+	CaptchaTurnstile: "cf-turnstile-response",
+}
+
+// ResponseField returns the POST field name that provider's widget submits
+// its token as.
+func ResponseField(provider CaptchaProvider) string {
+	return responseFields[provider]
+}
+
+// VerifyCaptcha checks token against provider using the tenant's shared
+// secret, returning true if the provider accepted it. A CaptchaNone
+// provider always passes.
+func VerifyCaptcha(ctx context.Context, provider CaptchaProvider, secret, token, remoteIP string) (bool, error) {
+	if provider == CaptchaNone {
+		return true, nil
+	}
+	verifyURL, ok := verifyURLs[provider]
+	if !ok {
+		return false, nil
+	}
+
+	form := url.Values{"secret": {secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return result.Success, nil
+}
@@ -0,0 +1,35 @@
+package antispam
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ContentScore scores text for spam-like heuristics: link count, the
+// fraction of letters that are uppercase, and known spam phrases. Higher
+// scores are more suspicious; compare against a tenant's configured
+// threshold.
+func ContentScore(text string, spamPhrases []string) int {
+	score := strings.Count(text, "http://") + strings.Count(text, "https://")
+
+	letters, upper := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= 20 && upper*100/letters >= 70 {
+		score += 2
+	}
+
+	lower := strings.ToLower(text)
+	for _, phrase := range spamPhrases {
+		if phrase != "" && strings.Contains(lower, strings.ToLower(phrase)) {
+			score += 3
+		}
+	}
+	return score
+}
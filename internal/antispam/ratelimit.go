@@ -0,0 +1,109 @@
+/*
+Package antispam implements the pluggable anti-spam pipeline: rate
+limiting, CAPTCHA verification and content heuristics.
+*/
+package antispam
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limiter answers "is key allowed to proceed right now". TokenBucketLimiter
+// is the in-memory default; RedisLimiter shares limits across instances.
+// LimiterFromEnv picks between them.
+type Limiter interface {
+	Allow(key string) bool
+}
+
+// LimiterFromEnv returns a RedisLimiter if REDIS_ADDR is set, otherwise an
+// in-memory TokenBucketLimiter. Both allow rps sustained requests per
+// second per key, with bursts up to burst.
+func LimiterFromEnv(rps float64, burst int) Limiter {
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		return NewRedisLimiter(redis.NewClient(&redis.Options{Addr: addr}), rps, burst)
+	}
+	return NewTokenBucketLimiter(rps, burst)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// bucketTTL is how long an idle per-key bucket is kept before the sweep
+// goroutine evicts it. Keys are attacker-influenced (e.g. checkSpam rate
+// limits on the unvalidated submitted email address), so without eviction
+// an attacker could grow buckets without bound just by varying the key.
+const bucketTTL = 10 * time.Minute
+
+// sweepInterval is how often the sweep goroutine scans for idle buckets.
+const sweepInterval = time.Minute
+
+// TokenBucketLimiter is an in-memory, per-key token bucket rate limiter.
+type TokenBucketLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	rps     float64
+	burst   int
+}
+
+// NewTokenBucketLimiter builds a limiter allowing rps sustained requests
+// per second per key, with bursts up to burst. It starts a background
+// goroutine that evicts buckets idle past bucketTTL, so the limiter's
+// memory stays bounded regardless of how many distinct keys it is asked
+// to rate-limit.
+func NewTokenBucketLimiter(rps float64, burst int) *TokenBucketLimiter {
+	l := &TokenBucketLimiter{buckets: map[string]*bucket{}, rps: rps, burst: burst}
+	go l.sweepLoop()
+	return l
+}
+
+// sweepLoop periodically evicts idle buckets until the process exits.
+func (l *TokenBucketLimiter) sweepLoop() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		l.sweep(now)
+	}
+}
+
+// sweep deletes every bucket that has been idle (no Allow call) for longer
+// than bucketTTL as of now.
+func (l *TokenBucketLimiter) sweep(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > bucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Allow reports whether key may proceed now, consuming a token if so.
+func (l *TokenBucketLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rps
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,66 @@
+package antispam
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLimiter is a Limiter backed by Redis, for deployments running more
+// than one instance that need their rate limits shared across processes.
+// It implements the same token-bucket algorithm as TokenBucketLimiter,
+// using a Lua script so the refill-and-consume sequence stays atomic.
+type RedisLimiter struct {
+	client *redis.Client
+	rps    float64
+	burst  int
+}
+
+// NewRedisLimiter builds a Limiter backed by client, allowing rps sustained
+// requests per second per key, with bursts up to burst.
+func NewRedisLimiter(client *redis.Client, rps float64, burst int) *RedisLimiter {
+	return &RedisLimiter{client: client, rps: rps, burst: burst}
+}
+
+// tokenBucketScript refills then consumes a token for a key in one atomic
+// round trip, storing the running token count and the last-refill time as
+// two keys that expire on their own if the key goes idle.
+var tokenBucketScript = redis.NewScript(`
+local tokens_key = KEYS[1] .. ":tokens"
+local last_key = KEYS[1] .. ":last"
+local rps = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local tokens = tonumber(redis.call("GET", tokens_key))
+local last = tonumber(redis.call("GET", last_key))
+if tokens == nil then
+	tokens = burst
+	last = now
+end
+
+tokens = math.min(burst, tokens + (now - last) * rps)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("SET", tokens_key, tokens, "EX", 3600)
+redis.call("SET", last_key, now, "EX", 3600)
+return allowed
+`)
+
+// Allow reports whether key may proceed now, consuming a token if so. A
+// Redis error fails open (allows the request) rather than blocking every
+// submission on a Redis outage.
+func (l *RedisLimiter) Allow(key string) bool {
+	now := float64(time.Now().UnixNano()) / float64(time.Second)
+	allowed, err := tokenBucketScript.Run(context.Background(), l.client, []string{key}, l.rps, l.burst, now).Int()
+	if err != nil {
+		return true
+	}
+	return allowed == 1
+}
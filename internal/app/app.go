@@ -0,0 +1,379 @@
+/*
+Package app holds the runtime state and request-handling building blocks
+shared by reviewer and inquirer: the mailer, tenant registry, anti-spam and
+email-verification config, submission store, and the retry worker and
+read-only admin API built on top of it. Each of reviewer/inquirer
+constructs its own App, so the two can run in the same process without
+sharing state; what differs between them (the submission's own fields,
+validation of its type-specific data, and how a submission's outbound
+email is addressed and worded) stays in each package.
+*/
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/presence-web-services/reviewer/internal/antispam"
+	"github.com/presence-web-services/reviewer/internal/mail"
+	"github.com/presence-web-services/reviewer/internal/store"
+	"github.com/presence-web-services/reviewer/internal/tenant"
+	"github.com/presence-web-services/reviewer/internal/verify"
+)
+
+// App holds the state a submission-handling service needs for every
+// request: the mailer, tenant registry, email-verification config,
+// submission store, and the rate-limiter/retry-worker bookkeeping built on
+// top of them.
+type App struct {
+	Mailer            mail.Mailer
+	Tenants           *tenant.Registry
+	VerifyDepth       verify.Depth
+	DisposableDomains map[string]bool
+	DataStore         store.Store
+
+	rateLimitersMu sync.Mutex
+	rateLimiters   map[string]antispam.Limiter
+
+	retryMu      sync.Mutex
+	retryNextAt  map[int64]time.Time
+	retryBackoff map[int64]time.Duration
+}
+
+const (
+	retryInitialBackoff = 30 * time.Second
+	retryMaxBackoff     = 30 * time.Minute
+)
+
+// New returns an App with its bookkeeping maps initialized. Callers still
+// need LoadEnvVars (or to set its fields directly, e.g. in tests) before
+// it can handle requests.
+func New() *App {
+	return &App{
+		rateLimiters: map[string]antispam.Limiter{},
+		retryNextAt:  map[int64]time.Time{},
+		retryBackoff: map[int64]time.Duration{},
+	}
+}
+
+// LoadEnvVars loads environment variables from a .env file and builds the
+// mailer, tenant registry, email verifier config and submission store from
+// them.
+func (a *App) LoadEnvVars() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: Could not load environment variables from .env file.")
+	}
+	a.Mailer = mail.FromEnv(os.Getenv("EMAIL_TO"), os.Getenv("SUBJECT"))
+	a.Tenants = tenant.FromEnv()
+	a.VerifyDepth = verify.DepthFromEnv()
+	a.DisposableDomains = verify.BlocklistFromEnv()
+	s, err := store.FromEnv()
+	if err != nil {
+		log.Println("Warning: Could not configure submission store:", err)
+	}
+	a.DataStore = s
+}
+
+// Authenticate authenticates the configured mailer.
+func (a *App) Authenticate() error {
+	return a.Mailer.Authenticate()
+}
+
+// RateLimiterFor returns (creating if necessary) the rate limiter for t.
+func (a *App) RateLimiterFor(t tenant.Config) antispam.Limiter {
+	a.rateLimitersMu.Lock()
+	defer a.rateLimitersMu.Unlock()
+
+	rl, ok := a.rateLimiters[t.ID]
+	if !ok {
+		rps, burst := t.RateLimit.RPS, t.RateLimit.Burst
+		if rps <= 0 {
+			rps = 1
+		}
+		if burst <= 0 {
+			burst = 5
+		}
+		rl = antispam.LimiterFromEnv(rps, burst)
+		a.rateLimiters[t.ID] = rl
+	}
+	return rl
+}
+
+// ClientIP extracts the request's remote IP, stripping the port.
+func ClientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// ResolveTenant looks up the tenant for request, first by Origin header and
+// then by a /submit/{tenant} path prefix, so one deployment can serve
+// several sites.
+func (a *App) ResolveTenant(request *http.Request) (tenant.Config, bool) {
+	if origin := request.Header.Get("Origin"); origin != "" {
+		if t, ok := a.Tenants.ByOrigin(origin); ok {
+			return t, true
+		}
+	}
+	if id := trimSubmitPrefix(request.URL.Path); id != "" {
+		if t, ok := a.Tenants.ByID(id); ok {
+			return t, true
+		}
+	}
+	return tenant.Config{}, false
+}
+
+// trimSubmitPrefix returns the tenant ID from a /submit/{tenant} path, or
+// "" if path does not have that prefix.
+func trimSubmitPrefix(path string) string {
+	const prefix = "/submit/"
+	if len(path) <= len(prefix) || path[:len(prefix)] != prefix {
+		return ""
+	}
+	return path[len(prefix):]
+}
+
+// VerifyPost reports an error unless method is POST, setting the Allow
+// header on response as required by RFC 7231 when it isn't.
+func VerifyPost(response http.ResponseWriter, method string) (status int, errMsg string) {
+	if method != "POST" {
+		response.Header().Set("Allow", "POST")
+		return http.StatusMethodNotAllowed, "Error: Method " + method + " not allowed. Only POST allowed."
+	}
+	return 0, ""
+}
+
+// CheckSpam runs t's anti-spam pipeline, if enabled, against a submission
+// from replyTo: per-IP and per-email rate limiting, CAPTCHA verification,
+// then content heuristics on content (the review for reviewer, the
+// message for inquirer).
+func (a *App) CheckSpam(request *http.Request, t tenant.Config, replyTo, content string) (status int, errMsg string) {
+	if !t.AntiSpam.Enabled {
+		return 0, ""
+	}
+
+	ip := ClientIP(request)
+	rl := a.RateLimiterFor(t)
+	if !rl.Allow("ip:"+ip) || !rl.Allow("email:"+replyTo) {
+		return http.StatusTooManyRequests, "Error: Too many submissions, please try again later."
+	}
+
+	provider := antispam.CaptchaProvider(t.AntiSpam.CaptchaProvider)
+	token := request.PostFormValue(antispam.ResponseField(provider))
+	ok, err := antispam.VerifyCaptcha(request.Context(), provider, t.AntiSpam.CaptchaSecret, token, ip)
+	if err != nil || !ok {
+		return http.StatusBadRequest, "Error: Captcha verification failed."
+	}
+
+	threshold := t.AntiSpam.ContentThreshold
+	if threshold == 0 {
+		threshold = 5
+	}
+	if antispam.ContentScore(content, t.AntiSpam.SpamPhrases) >= threshold {
+		return http.StatusBadRequest, "Error: Submission flagged as spam."
+	}
+	return 0, ""
+}
+
+// CheckEmail verifies replyTo is a plausible, non-disposable address.
+func (a *App) CheckEmail(replyTo string) (status int, errMsg string) {
+	if len(replyTo) < 5 || len(replyTo) > 50 {
+		return http.StatusBadRequest, "Error: Email is too short or too long."
+	}
+	switch verify.Email(context.Background(), replyTo, a.VerifyDepth, a.DisposableDomains) {
+	case nil:
+		return 0, ""
+	case verify.ErrInvalidSyntax:
+		return http.StatusBadRequest, "Error: Email is not a valid format."
+	case verify.ErrNoMX:
+		return http.StatusBadRequest, "Error: Domain given is not a valid email domain."
+	case verify.ErrMailboxRejected:
+		return http.StatusBadRequest, "Error: Email address was rejected by its mail server."
+	case verify.ErrDisposable:
+		return http.StatusBadRequest, "Error: Disposable email addresses are not allowed."
+	default:
+		return http.StatusBadRequest, "Error: Could not verify email address."
+	}
+}
+
+// CheckHP ensures the honeypot field is not populated.
+func CheckHP(hp string) (status int, errMsg string) {
+	if hp != "" {
+		return http.StatusBadRequest, "Error: Please, no robots!"
+	}
+	return 0, ""
+}
+
+// SaveSubmission persists sub, so a failed send does not lose it, and
+// returns its storage ID (0 if no Store is configured or the save itself
+// failed).
+func (a *App) SaveSubmission(sub *store.Submission) int64 {
+	if a.DataStore == nil {
+		return 0
+	}
+	if err := a.DataStore.Insert(context.Background(), sub); err != nil {
+		log.Println("Warning: Could not persist submission:", err)
+		return 0
+	}
+	return sub.ID
+}
+
+// RecordEmailOutcome updates the persisted submission storeID (if any, and
+// if a Store is configured) with the outcome of sending: status/errMsg as
+// left by the handler pipeline.
+func (a *App) RecordEmailOutcome(storeID int64, status int, errMsg string) {
+	if a.DataStore == nil || storeID == 0 {
+		return
+	}
+	st, msg := store.StatusSent, ""
+	if status != http.StatusOK {
+		st, msg = store.StatusFailed, errMsg
+	}
+	if err := a.DataStore.UpdateEmailStatus(context.Background(), storeID, st, msg); err != nil {
+		log.Println("Warning: Could not update persisted submission:", err)
+	}
+}
+
+// AuthorizedAdmin reports whether request carries the bearer token
+// configured via ADMIN_TOKEN. An unset ADMIN_TOKEN denies every request.
+// The comparison is done on SHA-256 hashes of equal length via
+// subtle.ConstantTimeCompare, rather than ==, so neither the token's
+// length nor its content can be inferred from response timing.
+func AuthorizedAdmin(request *http.Request) bool {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		return false
+	}
+	want := sha256.Sum256([]byte("Bearer " + token))
+	got := sha256.Sum256([]byte(request.Header.Get("Authorization")))
+	return subtle.ConstantTimeCompare(want[:], got[:]) == 1
+}
+
+// SubmissionsHandler serves GET /submissions?tenant=... as JSON, so site
+// owners can browse submissions even when email delivery is broken. It
+// requires an Authorization: Bearer <ADMIN_TOKEN> header matching the
+// configured admin token.
+func (a *App) SubmissionsHandler(response http.ResponseWriter, request *http.Request) {
+	if !AuthorizedAdmin(request) {
+		http.Error(response, "Error: Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	if request.Method != http.MethodGet {
+		response.Header().Set("Allow", "GET")
+		http.Error(response, "Error: Method "+request.Method+" not allowed. Only GET allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	if a.DataStore == nil {
+		http.Error(response, "Error: No submission store is configured.", http.StatusNotImplemented)
+		return
+	}
+	subs, err := a.DataStore.List(request.Context(), request.URL.Query().Get("tenant"))
+	if err != nil {
+		http.Error(response, "Error: Could not list submissions.", http.StatusInternalServerError)
+		return
+	}
+	response.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(response).Encode(subs)
+}
+
+// RetrySender builds and sends the outbound email for a persisted
+// submission being retried, returning an error if delivery failed.
+// Reviewer and inquirer each supply their own, since how a submission's
+// email is addressed and worded differs (e.g. reviewer's rating-driven
+// escalation and subject template).
+type RetrySender func(ctx context.Context, sub store.Submission, t tenant.Config) error
+
+// StartRetryWorker periodically resends submissions whose email failed,
+// backing off exponentially per submission so a down mail server isn't
+// hammered. It returns a function that stops the worker.
+func (a *App) StartRetryWorker(interval time.Duration, send RetrySender) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.retryFailedSubmissions(send)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// retryFailedSubmissions resends every persisted submission due for retry.
+func (a *App) retryFailedSubmissions(send RetrySender) {
+	if a.DataStore == nil {
+		return
+	}
+	failed, err := a.DataStore.ListFailed(context.Background())
+	if err != nil {
+		log.Println("Warning: Could not list failed submissions for retry:", err)
+		return
+	}
+	for _, sub := range failed {
+		if a.dueForRetry(sub.ID) {
+			a.retrySubmission(sub, send)
+		}
+	}
+}
+
+// retrySubmission re-sends a single persisted submission via send and
+// updates its stored outcome.
+func (a *App) retrySubmission(sub store.Submission, send RetrySender) {
+	t, ok := a.Tenants.ByID(sub.Tenant)
+	if !ok {
+		return
+	}
+	if err := send(context.Background(), sub, t); err != nil {
+		a.backOffRetry(sub.ID)
+		a.DataStore.UpdateEmailStatus(context.Background(), sub.ID, store.StatusFailed, err.Error())
+		return
+	}
+	a.clearRetryBackoff(sub.ID)
+	a.DataStore.UpdateEmailStatus(context.Background(), sub.ID, store.StatusSent, "")
+}
+
+// dueForRetry reports whether id's backoff period has elapsed.
+func (a *App) dueForRetry(id int64) bool {
+	a.retryMu.Lock()
+	defer a.retryMu.Unlock()
+	next, ok := a.retryNextAt[id]
+	return !ok || !time.Now().Before(next)
+}
+
+// backOffRetry doubles id's backoff (starting at retryInitialBackoff, capped
+// at retryMaxBackoff) and schedules its next retry.
+func (a *App) backOffRetry(id int64) {
+	a.retryMu.Lock()
+	defer a.retryMu.Unlock()
+	d := a.retryBackoff[id]
+	if d == 0 {
+		d = retryInitialBackoff
+	} else if d *= 2; d > retryMaxBackoff {
+		d = retryMaxBackoff
+	}
+	a.retryBackoff[id] = d
+	a.retryNextAt[id] = time.Now().Add(d)
+}
+
+// clearRetryBackoff forgets id's backoff state after a successful send.
+func (a *App) clearRetryBackoff(id int64) {
+	a.retryMu.Lock()
+	defer a.retryMu.Unlock()
+	delete(a.retryBackoff, id)
+	delete(a.retryNextAt, id)
+}
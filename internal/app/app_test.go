@@ -0,0 +1,144 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/presence-web-services/reviewer/internal/tenant"
+)
+
+func testApp() *App {
+	a := New()
+	a.Tenants = tenant.NewRegistry([]tenant.Config{{ID: "default", AllowedOrigin: "https://example.com"}})
+	return a
+}
+
+func TestAuthorizedAdmin(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "s3cret")
+
+	req := httptest.NewRequest(http.MethodGet, "/submissions", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	if !AuthorizedAdmin(req) {
+		t.Error("AuthorizedAdmin(correct token) = false, want true")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/submissions", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	if AuthorizedAdmin(req) {
+		t.Error("AuthorizedAdmin(wrong token) = true, want false")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/submissions", nil)
+	if AuthorizedAdmin(req) {
+		t.Error("AuthorizedAdmin(no header) = true, want false")
+	}
+}
+
+func TestAuthorizedAdminUnsetTokenDeniesEverything(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "")
+
+	req := httptest.NewRequest(http.MethodGet, "/submissions", nil)
+	req.Header.Set("Authorization", "Bearer anything")
+	if AuthorizedAdmin(req) {
+		t.Error("AuthorizedAdmin() with no ADMIN_TOKEN configured should deny every request")
+	}
+}
+
+func TestResolveTenantByOrigin(t *testing.T) {
+	a := testApp()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Origin", "https://example.com")
+	tc, ok := a.ResolveTenant(req)
+	if !ok || tc.ID != "default" {
+		t.Errorf("ResolveTenant(origin) = %+v, ok = %v", tc, ok)
+	}
+}
+
+func TestResolveTenantByPathPrefix(t *testing.T) {
+	a := testApp()
+	req := httptest.NewRequest(http.MethodPost, "/submit/default", nil)
+	tc, ok := a.ResolveTenant(req)
+	if !ok || tc.ID != "default" {
+		t.Errorf("ResolveTenant(path) = %+v, ok = %v", tc, ok)
+	}
+}
+
+func TestResolveTenantUnknown(t *testing.T) {
+	a := testApp()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Origin", "https://unknown.example.com")
+	if _, ok := a.ResolveTenant(req); ok {
+		t.Error("ResolveTenant(unknown origin) should not match")
+	}
+}
+
+func TestVerifyPost(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if status, _ := VerifyPost(rec, http.MethodPost); status != 0 {
+		t.Errorf("VerifyPost(POST) status = %d, want 0", status)
+	}
+
+	rec = httptest.NewRecorder()
+	status, _ := VerifyPost(rec, http.MethodGet)
+	if status != http.StatusMethodNotAllowed {
+		t.Errorf("VerifyPost(GET) status = %d, want %d", status, http.StatusMethodNotAllowed)
+	}
+	if got := rec.Header().Get("Allow"); got != "POST" {
+		t.Errorf("Allow header = %q, want %q", got, "POST")
+	}
+}
+
+func TestCheckHP(t *testing.T) {
+	if status, _ := CheckHP(""); status != 0 {
+		t.Errorf("CheckHP(\"\") status = %d, want 0", status)
+	}
+	if status, _ := CheckHP("bot filled this in"); status != http.StatusBadRequest {
+		t.Errorf("CheckHP(filled) status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestRateLimiterForReusesLimiter(t *testing.T) {
+	a := testApp()
+	tc := tenant.Config{ID: "default"}
+	rl1 := a.RateLimiterFor(tc)
+	rl2 := a.RateLimiterFor(tc)
+	if rl1 != rl2 {
+		t.Error("RateLimiterFor should return the same limiter for the same tenant")
+	}
+}
+
+func TestRetryBackoffDoublesAndCaps(t *testing.T) {
+	a := testApp()
+	const id = int64(1)
+
+	if !a.dueForRetry(id) {
+		t.Error("a submission with no recorded attempt should be due")
+	}
+
+	a.backOffRetry(id)
+	first := a.retryBackoff[id]
+	if first != retryInitialBackoff {
+		t.Errorf("first backoff = %v, want %v", first, retryInitialBackoff)
+	}
+	if a.dueForRetry(id) {
+		t.Error("a submission should not be due immediately after backing off")
+	}
+
+	a.backOffRetry(id)
+	if a.retryBackoff[id] != first*2 {
+		t.Errorf("second backoff = %v, want %v", a.retryBackoff[id], first*2)
+	}
+
+	for i := 0; i < 10; i++ {
+		a.backOffRetry(id)
+	}
+	if a.retryBackoff[id] != retryMaxBackoff {
+		t.Errorf("backoff should cap at %v, got %v", retryMaxBackoff, a.retryBackoff[id])
+	}
+
+	a.clearRetryBackoff(id)
+	if !a.dueForRetry(id) {
+		t.Error("a submission should be due again after clearRetryBackoff")
+	}
+}
@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/presence-web-services/gmailer/v2"
+)
+
+// errNotAuthenticated is returned by Send when the underlying gmailer
+// Config has no authenticated Service, e.g. because Authenticate was
+// skipped (no ClientID configured) or failed.
+var errNotAuthenticated = errors.New("mail: gmail mailer is not authenticated")
+
+// GmailMailer sends email via Gmail OAuth using the gmailer package.
+type GmailMailer struct {
+	config gmailer.Config
+}
+
+// newGmailMailerFromEnv builds a GmailMailer from the gmailer OAuth
+// credentials in CLIENT_ID, CLIENT_SECRET, ACCESS_TOKEN, REFRESH_TOKEN and
+// EMAIL_FROM.
+func newGmailMailerFromEnv(emailTo, subject string) *GmailMailer {
+	return &GmailMailer{config: gmailer.Config{
+		ClientID:     os.Getenv("CLIENT_ID"),
+		ClientSecret: os.Getenv("CLIENT_SECRET"),
+		AccessToken:  os.Getenv("ACCESS_TOKEN"),
+		RefreshToken: os.Getenv("REFRESH_TOKEN"),
+		EmailTo:      emailTo,
+		EmailFrom:    os.Getenv("EMAIL_FROM"),
+		Subject:      subject,
+	}}
+}
+
+// Authenticate authenticates the underlying gmailer config. If no client ID
+// is configured, it is treated as "Gmail backend unused" and skipped, so an
+// unconfigured backend does not block startup.
+func (m *GmailMailer) Authenticate() error {
+	if m.config.ClientID == "" {
+		return nil
+	}
+	return m.config.Authenticate()
+}
+
+// Send delivers msg on a private copy of the authenticated config, so
+// concurrent sends never share ReplyTo/Body. A non-empty msg.To, msg.From
+// or msg.Subject overrides the Mailer's configured default, so one Mailer
+// can route mail for several tenants. Send returns errNotAuthenticated
+// instead of sending if Authenticate was never completed, so a missing or
+// failed Gmail credential surfaces as a per-request error rather than a
+// nil-pointer panic.
+func (m *GmailMailer) Send(ctx context.Context, msg Message) error {
+	if m.config.Service == nil {
+		return errNotAuthenticated
+	}
+	cfg := m.config
+	cfg.ReplyTo = msg.ReplyTo
+	cfg.Body = msg.Body
+	if msg.To != "" {
+		cfg.EmailTo = msg.To
+	}
+	if msg.From != "" {
+		cfg.EmailFrom = msg.From
+	}
+	if msg.Subject != "" {
+		cfg.Subject = msg.Subject
+	}
+	return cfg.Send()
+}
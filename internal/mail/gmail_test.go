@@ -0,0 +1,23 @@
+package mail
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGmailMailerSendWithoutAuthenticate(t *testing.T) {
+	m := &GmailMailer{}
+	if err := m.Send(context.Background(), Message{ReplyTo: "alice@example.com"}); err != errNotAuthenticated {
+		t.Errorf("Send() error = %v, want %v", err, errNotAuthenticated)
+	}
+}
+
+func TestGmailMailerAuthenticateSkippedWithoutClientID(t *testing.T) {
+	m := &GmailMailer{}
+	if err := m.Authenticate(); err != nil {
+		t.Fatalf("Authenticate() with no ClientID should no-op, got: %v", err)
+	}
+	if err := m.Send(context.Background(), Message{}); err != errNotAuthenticated {
+		t.Errorf("Send() after no-op Authenticate should still refuse to send, got: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Package mail defines a pluggable interface for sending outbound email, so
+reviewer and inquirer are not hard-wired to Gmail OAuth.
+*/
+package mail
+
+import "context"
+
+// Message is the outbound email a Mailer is asked to send. To, From and
+// Subject are optional overrides of the Mailer's configured defaults, so a
+// single Mailer can serve several tenants with different routing.
+type Message struct {
+	ReplyTo string
+	Body    string
+	To      string
+	From    string
+	Subject string
+}
+
+// Mailer sends Messages on behalf of a configured sender identity.
+type Mailer interface {
+	// Authenticate prepares the Mailer to send, performing any OAuth or
+	// connection handshake up front. It is called once at startup.
+	Authenticate() error
+	// Send delivers msg, returning an error if delivery failed.
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv builds a Mailer from environment variables. If SMTP_HOST is set,
+// a plain SMTPMailer is used; otherwise it falls back to Gmail OAuth using
+// the CLIENT_ID/CLIENT_SECRET/ACCESS_TOKEN/REFRESH_TOKEN variables.
+func FromEnv(emailTo, subject string) Mailer {
+	if smtpConfigured() {
+		return NewSMTPMailerFromEnv(emailTo, subject)
+	}
+	return newGmailMailerFromEnv(emailTo, subject)
+}
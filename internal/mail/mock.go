@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// MockMailer records every Message it is asked to send, for use in tests.
+// It is safe for concurrent use.
+type MockMailer struct {
+	mu   sync.Mutex
+	sent []Message
+
+	// SendErr, if set, is returned by Send instead of recording the message.
+	SendErr error
+}
+
+// Authenticate always succeeds.
+func (m *MockMailer) Authenticate() error { return nil }
+
+// Send records msg, or returns SendErr if it is set.
+func (m *MockMailer) Send(ctx context.Context, msg Message) error {
+	if m.SendErr != nil {
+		return m.SendErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Messages returns a copy of every Message sent so far.
+func (m *MockMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Message, len(m.sent))
+	copy(out, m.sent)
+	return out
+}
@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMockMailerRecordsMessages(t *testing.T) {
+	m := &MockMailer{}
+	msg := Message{ReplyTo: "alice@example.com", Body: "hello"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	got := m.Messages()
+	if len(got) != 1 || got[0] != msg {
+		t.Errorf("Messages() = %v, want [%v]", got, msg)
+	}
+}
+
+func TestMockMailerSendErr(t *testing.T) {
+	wantErr := errors.New("boom")
+	m := &MockMailer{SendErr: wantErr}
+	if err := m.Send(context.Background(), Message{}); err != wantErr {
+		t.Errorf("Send() error = %v, want %v", err, wantErr)
+	}
+	if len(m.Messages()) != 0 {
+		t.Errorf("Messages() should be empty after a failed send")
+	}
+}
@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"os"
+)
+
+// SMTPMailer sends email through a plain SMTP relay, configured via the
+// SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS and SMTP_FROM environment
+// variables.
+type SMTPMailer struct {
+	Host, Port, User, Pass, From, To, Subject string
+}
+
+// smtpConfigured reports whether the SMTP_HOST environment variable is set.
+func smtpConfigured() bool {
+	return os.Getenv("SMTP_HOST") != ""
+}
+
+// NewSMTPMailerFromEnv builds an SMTPMailer from the SMTP_* environment
+// variables.
+func NewSMTPMailerFromEnv(emailTo, subject string) *SMTPMailer {
+	return &SMTPMailer{
+		Host:    os.Getenv("SMTP_HOST"),
+		Port:    os.Getenv("SMTP_PORT"),
+		User:    os.Getenv("SMTP_USER"),
+		Pass:    os.Getenv("SMTP_PASS"),
+		From:    os.Getenv("SMTP_FROM"),
+		To:      emailTo,
+		Subject: subject,
+	}
+}
+
+// Authenticate is a no-op; net/smtp authenticates per-connection in Send.
+func (m *SMTPMailer) Authenticate() error {
+	return nil
+}
+
+// Send delivers msg over SMTP using PLAIN auth against Host:Port. A
+// non-empty msg.To, msg.From or msg.Subject overrides the Mailer's
+// configured default, so one Mailer can route mail for several tenants.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	to, from, body := m.resolveMessage(msg)
+	addr := m.Host + ":" + m.Port
+	auth := smtp.PlainAuth("", m.User, m.Pass, m.Host)
+	return smtp.SendMail(addr, auth, from, []string{to}, body)
+}
+
+// resolveMessage applies msg's To/From/Subject overrides on top of m's
+// configured defaults and renders the RFC822 header block and body, so the
+// message bytes can be asserted in tests without dialing a real server.
+func (m *SMTPMailer) resolveMessage(msg Message) (to, from string, body []byte) {
+	to, from, subject := m.To, m.From, m.Subject
+	if msg.To != "" {
+		to = msg.To
+	}
+	if msg.From != "" {
+		from = msg.From
+	}
+	if msg.Subject != "" {
+		subject = msg.Subject
+	}
+
+	headers := fmt.Sprintf("From: %s\r\nTo: %s\r\nReply-To: %s\r\nSubject: %s\r\n\r\n", from, to, msg.ReplyTo, subject)
+	return to, from, []byte(headers + msg.Body)
+}
@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSMTPMailerResolveMessageDefaults(t *testing.T) {
+	m := &SMTPMailer{From: "site@example.com", To: "owner@example.com", Subject: "New review"}
+	to, from, body := m.resolveMessage(Message{ReplyTo: "alice@example.com", Body: "hello"})
+
+	if to != "owner@example.com" {
+		t.Errorf("to = %q, want owner@example.com", to)
+	}
+	if from != "site@example.com" {
+		t.Errorf("from = %q, want site@example.com", from)
+	}
+	want := "From: site@example.com\r\nTo: owner@example.com\r\nReply-To: alice@example.com\r\nSubject: New review\r\n\r\nhello"
+	if string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestSMTPMailerResolveMessageOverrides(t *testing.T) {
+	m := &SMTPMailer{From: "site@example.com", To: "owner@example.com", Subject: "New review"}
+	to, from, body := m.resolveMessage(Message{
+		ReplyTo: "alice@example.com",
+		Body:    "hello",
+		To:      "escalation@example.com",
+		From:    "other-site@example.com",
+		Subject: "[1★] New review from Alice",
+	})
+
+	if to != "escalation@example.com" {
+		t.Errorf("to = %q, want escalation@example.com", to)
+	}
+	if from != "other-site@example.com" {
+		t.Errorf("from = %q, want other-site@example.com", from)
+	}
+	if !strings.Contains(string(body), "Subject: [1★] New review from Alice\r\n") {
+		t.Errorf("body missing overridden subject header: %q", body)
+	}
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an in-process Store, useful for tests and for running
+// without a configured database. Submissions do not survive a restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	subs   []Submission
+	nextID int64
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Insert saves sub and assigns it the next ID.
+func (m *MemoryStore) Insert(ctx context.Context, sub *Submission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	sub.ID = m.nextID
+	m.subs = append(m.subs, *sub)
+	return nil
+}
+
+// UpdateEmailStatus updates the status and error of the submission with the
+// given id, if it exists.
+func (m *MemoryStore) UpdateEmailStatus(ctx context.Context, id int64, status, errMsg string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.subs {
+		if m.subs[i].ID == id {
+			m.subs[i].EmailStatus = status
+			m.subs[i].EmailError = errMsg
+			return nil
+		}
+	}
+	return nil
+}
+
+// List returns submissions for tenant, or every submission if tenant is "".
+func (m *MemoryStore) List(ctx context.Context, tenant string) ([]Submission, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Submission
+	for _, s := range m.subs {
+		if tenant == "" || s.Tenant == tenant {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// ListFailed returns every submission whose EmailStatus is StatusFailed.
+func (m *MemoryStore) ListFailed(ctx context.Context) ([]Submission, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Submission
+	for _, s := range m.subs {
+		if s.EmailStatus == StatusFailed {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
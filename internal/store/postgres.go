@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresStore persists submissions to a Postgres database.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore opens dsn and ensures the submissions schema exists.
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS submissions (
+			id SERIAL PRIMARY KEY,
+			tenant TEXT NOT NULL,
+			received_at TIMESTAMPTZ NOT NULL,
+			remote_ip TEXT NOT NULL,
+			name TEXT NOT NULL,
+			email TEXT NOT NULL,
+			stars TEXT NOT NULL,
+			message TEXT NOT NULL,
+			email_status TEXT NOT NULL,
+			email_error TEXT NOT NULL
+		)
+	`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Insert saves sub and sets sub.ID to the assigned row ID.
+func (s *PostgresStore) Insert(ctx context.Context, sub *Submission) error {
+	return s.db.QueryRowContext(ctx,
+		`INSERT INTO submissions (tenant, received_at, remote_ip, name, email, stars, message, email_status, email_error)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		sub.Tenant, sub.ReceivedAt, sub.RemoteIP, sub.Name, sub.Email, sub.Stars, sub.Message, sub.EmailStatus, sub.EmailError,
+	).Scan(&sub.ID)
+}
+
+// UpdateEmailStatus records the outcome of attempting to send sub's email.
+func (s *PostgresStore) UpdateEmailStatus(ctx context.Context, id int64, status, errMsg string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE submissions SET email_status = $1, email_error = $2 WHERE id = $3`, status, errMsg, id)
+	return err
+}
+
+// List returns submissions for tenant, or every tenant if tenant is "".
+func (s *PostgresStore) List(ctx context.Context, tenant string) ([]Submission, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if tenant == "" {
+		rows, err = s.db.QueryContext(ctx, `SELECT `+submissionColumns+` FROM submissions ORDER BY id`)
+	} else {
+		rows, err = s.db.QueryContext(ctx, `SELECT `+submissionColumns+` FROM submissions WHERE tenant = $1 ORDER BY id`, tenant)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanSubmissions(rows)
+}
+
+// ListFailed returns every submission whose last send attempt failed.
+func (s *PostgresStore) ListFailed(ctx context.Context) ([]Submission, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+submissionColumns+` FROM submissions WHERE email_status = $1 ORDER BY id`, StatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanSubmissions(rows)
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore persists submissions to a local SQLite database.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if necessary) the SQLite database at path
+// and ensures its schema exists.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS submissions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			tenant TEXT NOT NULL,
+			received_at DATETIME NOT NULL,
+			remote_ip TEXT NOT NULL,
+			name TEXT NOT NULL,
+			email TEXT NOT NULL,
+			stars TEXT NOT NULL,
+			message TEXT NOT NULL,
+			email_status TEXT NOT NULL,
+			email_error TEXT NOT NULL
+		)
+	`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Insert saves sub and sets sub.ID to the assigned row ID.
+func (s *SQLiteStore) Insert(ctx context.Context, sub *Submission) error {
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO submissions (tenant, received_at, remote_ip, name, email, stars, message, email_status, email_error)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sub.Tenant, sub.ReceivedAt, sub.RemoteIP, sub.Name, sub.Email, sub.Stars, sub.Message, sub.EmailStatus, sub.EmailError)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	sub.ID = id
+	return nil
+}
+
+// UpdateEmailStatus records the outcome of attempting to send sub's email.
+func (s *SQLiteStore) UpdateEmailStatus(ctx context.Context, id int64, status, errMsg string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE submissions SET email_status = ?, email_error = ? WHERE id = ?`, status, errMsg, id)
+	return err
+}
+
+// List returns submissions for tenant, or every tenant if tenant is "".
+func (s *SQLiteStore) List(ctx context.Context, tenant string) ([]Submission, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if tenant == "" {
+		rows, err = s.db.QueryContext(ctx, `SELECT `+submissionColumns+` FROM submissions ORDER BY id`)
+	} else {
+		rows, err = s.db.QueryContext(ctx, `SELECT `+submissionColumns+` FROM submissions WHERE tenant = ? ORDER BY id`, tenant)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanSubmissions(rows)
+}
+
+// ListFailed returns every submission whose last send attempt failed.
+func (s *SQLiteStore) ListFailed(ctx context.Context) ([]Submission, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+submissionColumns+` FROM submissions WHERE email_status = ? ORDER BY id`, StatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanSubmissions(rows)
+}
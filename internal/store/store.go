@@ -0,0 +1,78 @@
+/*
+Package store persists submissions so a failed email send does not lose the
+data, and lets site owners browse submissions even when email delivery is
+broken.
+*/
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Email delivery states tracked for a persisted submission.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// Submission is a persisted copy of a reviewer/inquirer form submission.
+// Name and Stars are blank for inquirer submissions, which have no rating.
+type Submission struct {
+	ID          int64
+	Tenant      string
+	ReceivedAt  time.Time
+	RemoteIP    string
+	Name        string
+	Email       string
+	Stars       string
+	Message     string
+	EmailStatus string
+	EmailError  string
+}
+
+// Store persists submissions and tracks their email delivery outcome.
+type Store interface {
+	// Insert saves sub and sets sub.ID to the assigned row ID.
+	Insert(ctx context.Context, sub *Submission) error
+	// UpdateEmailStatus records the outcome of attempting to send sub's email.
+	UpdateEmailStatus(ctx context.Context, id int64, status, errMsg string) error
+	// List returns submissions for tenant, or every tenant if tenant is "".
+	List(ctx context.Context, tenant string) ([]Submission, error)
+	// ListFailed returns every submission whose last send attempt failed.
+	ListFailed(ctx context.Context) ([]Submission, error)
+}
+
+// FromEnv builds a Store from STORE_DRIVER ("sqlite" or "postgres") and
+// STORE_DSN. It returns (nil, nil) if STORE_DRIVER is unset, so persistence
+// stays opt-in for deployments that don't need it.
+func FromEnv() (Store, error) {
+	switch driver := os.Getenv("STORE_DRIVER"); driver {
+	case "":
+		return nil, nil
+	case "sqlite":
+		return NewSQLiteStore(os.Getenv("STORE_DSN"))
+	case "postgres":
+		return NewPostgresStore(os.Getenv("STORE_DSN"))
+	default:
+		return nil, fmt.Errorf("store: unknown STORE_DRIVER %q", driver)
+	}
+}
+
+const submissionColumns = "id, tenant, received_at, remote_ip, name, email, stars, message, email_status, email_error"
+
+func scanSubmissions(rows *sql.Rows) ([]Submission, error) {
+	var out []Submission
+	for rows.Next() {
+		var sub Submission
+		if err := rows.Scan(&sub.ID, &sub.Tenant, &sub.ReceivedAt, &sub.RemoteIP, &sub.Name, &sub.Email, &sub.Stars, &sub.Message, &sub.EmailStatus, &sub.EmailError); err != nil {
+			return nil, err
+		}
+		out = append(out, sub)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreInsertUpdateList(t *testing.T) {
+	s := NewMemoryStore()
+	sub := &Submission{
+		Tenant:      "default",
+		ReceivedAt:  time.Now(),
+		RemoteIP:    "127.0.0.1",
+		Name:        "Ada",
+		Email:       "ada@example.com",
+		Stars:       "5",
+		Message:     "Great!",
+		EmailStatus: StatusPending,
+	}
+	if err := s.Insert(context.Background(), sub); err != nil {
+		t.Fatalf("Insert() = %v", err)
+	}
+	if sub.ID == 0 {
+		t.Fatal("Insert() did not assign an ID")
+	}
+
+	if err := s.UpdateEmailStatus(context.Background(), sub.ID, StatusFailed, "smtp timeout"); err != nil {
+		t.Fatalf("UpdateEmailStatus() = %v", err)
+	}
+
+	failed, err := s.ListFailed(context.Background())
+	if err != nil {
+		t.Fatalf("ListFailed() = %v", err)
+	}
+	if len(failed) != 1 || failed[0].EmailError != "smtp timeout" {
+		t.Fatalf("ListFailed() = %+v, want one submission with the updated error", failed)
+	}
+
+	all, err := s.List(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("List(%q) = %v", "default", err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("List(%q) = %d submissions, want 1", "default", len(all))
+	}
+
+	none, err := s.List(context.Background(), "other")
+	if err != nil {
+		t.Fatalf("List(%q) = %v", "other", err)
+	}
+	if len(none) != 0 {
+		t.Fatalf("List(%q) = %d submissions, want 0", "other", len(none))
+	}
+}
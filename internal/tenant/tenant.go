@@ -0,0 +1,150 @@
+/*
+Package tenant holds the per-site configuration that lets a single
+reviewer/inquirer deployment serve more than one website.
+*/
+package tenant
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+)
+
+// RateLimit sets the token-bucket rate and burst a tenant applies to each
+// client IP and to each submitted email address when its anti-spam
+// pipeline is enabled; it does not cap the tenant's total submissions.
+type RateLimit struct {
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+}
+
+// AntiSpam configures the optional rate limiting, CAPTCHA and content
+// heuristic checks a tenant's submissions are run through.
+type AntiSpam struct {
+	Enabled          bool     `json:"enabled"`
+	CaptchaProvider  string   `json:"captcha_provider"`
+	CaptchaSecret    string   `json:"captcha_secret"`
+	ContentThreshold int      `json:"content_threshold"`
+	SpamPhrases      []string `json:"spam_phrases"`
+}
+
+// Config describes one tenant: the site allowed to submit to it, and where
+// its mail should be routed.
+type Config struct {
+	ID                string    `json:"id"`
+	AllowedOrigin     string    `json:"allowed_origin"`
+	EmailTo           string    `json:"email_to"`
+	EmailFrom         string    `json:"email_from"`
+	Subject           string    `json:"subject"`
+	HoneypotFieldName string    `json:"honeypot_field_name"`
+	RateLimit         RateLimit `json:"rate_limit"`
+	AntiSpam          AntiSpam  `json:"anti_spam"`
+
+	// SubjectTemplate, if set, is a text/template string rendered per
+	// submission (with .Name, .Stars and .Review) to produce the email
+	// subject, overriding the plain Subject above. Only reviewer renders
+	// it; inquirer always sends the plain Subject.
+	SubjectTemplate string `json:"subject_template"`
+
+	// EscalationEmailTo, if set, replaces EmailTo for reviews rated 2
+	// stars or lower, so negative feedback reaches an owner immediately.
+	EscalationEmailTo string `json:"escalation_email_to"`
+
+	// AllowedRecipients, if non-empty, is the set of addresses mail for
+	// this tenant may be sent to; see RecipientAllowed. Leave empty to
+	// allow any address (the common case for a tenant with a single,
+	// fixed EmailTo).
+	AllowedRecipients []string `json:"allowed_recipients"`
+}
+
+// Registry is a set of tenants, looked up by allowed origin or ID.
+type Registry struct {
+	byOrigin map[string]Config
+	byID     map[string]Config
+}
+
+// NewRegistry indexes configs by origin and ID, defaulting an unset
+// honeypot field name to "hp" to match the historical single-tenant field.
+// It is exported mainly so callers can build a Registry for tests without
+// writing a tenants file to disk.
+func NewRegistry(configs []Config) *Registry {
+	return newRegistry(configs)
+}
+
+// newRegistry indexes configs by origin and ID, defaulting an unset
+// honeypot field name to "hp" to match the historical single-tenant field.
+func newRegistry(configs []Config) *Registry {
+	r := &Registry{byOrigin: map[string]Config{}, byID: map[string]Config{}}
+	for _, c := range configs {
+		if c.HoneypotFieldName == "" {
+			c.HoneypotFieldName = "hp"
+		}
+		r.byOrigin[c.AllowedOrigin] = c
+		r.byID[c.ID] = c
+	}
+	return r
+}
+
+// Load reads a list of tenant configs from a JSON file.
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, err
+	}
+	return newRegistry(configs), nil
+}
+
+// FromEnv builds a Registry from the TENANTS_FILE it names, if set.
+// Otherwise it falls back to a single "default" tenant built from the
+// legacy SITE/EMAIL_TO/EMAIL_FROM/SUBJECT variables, so single-site
+// deployments need no config file.
+func FromEnv() *Registry {
+	if path := os.Getenv("TENANTS_FILE"); path != "" {
+		r, err := Load(path)
+		if err != nil {
+			log.Println("Warning: Could not load TENANTS_FILE, falling back to single-tenant env vars:", err)
+		} else {
+			return r
+		}
+	}
+	return newRegistry([]Config{{
+		ID:                "default",
+		AllowedOrigin:     "https://" + os.Getenv("SITE"),
+		EmailTo:           os.Getenv("EMAIL_TO"),
+		EmailFrom:         os.Getenv("EMAIL_FROM"),
+		Subject:           os.Getenv("SUBJECT"),
+		SubjectTemplate:   os.Getenv("SUBJECT_TEMPLATE"),
+		EscalationEmailTo: os.Getenv("ESCALATION_EMAIL_TO"),
+	}})
+}
+
+// RecipientAllowed reports whether addr may be used as a mail recipient for
+// this tenant. If AllowedRecipients is empty, every address is allowed, so
+// tenants that don't configure it need no changes.
+func (c Config) RecipientAllowed(addr string) bool {
+	if len(c.AllowedRecipients) == 0 {
+		return true
+	}
+	for _, a := range c.AllowedRecipients {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// ByOrigin looks up the tenant whose AllowedOrigin matches origin.
+func (r *Registry) ByOrigin(origin string) (Config, bool) {
+	c, ok := r.byOrigin[origin]
+	return c, ok
+}
+
+// ByID looks up the tenant with the given ID.
+func (r *Registry) ByID(id string) (Config, bool) {
+	c, ok := r.byID[id]
+	return c, ok
+}
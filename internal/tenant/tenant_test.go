@@ -0,0 +1,65 @@
+package tenant
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAndLookup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tenants.json")
+	body := `[
+		{"id": "a", "allowed_origin": "https://a.example.com", "email_to": "a@example.com"},
+		{"id": "b", "allowed_origin": "https://b.example.com", "email_to": "b@example.com", "honeypot_field_name": "website"}
+	]`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	a, ok := r.ByOrigin("https://a.example.com")
+	if !ok || a.EmailTo != "a@example.com" || a.HoneypotFieldName != "hp" {
+		t.Errorf("ByOrigin(a) = %+v, ok = %v", a, ok)
+	}
+
+	b, ok := r.ByID("b")
+	if !ok || b.HoneypotFieldName != "website" {
+		t.Errorf("ByID(b) = %+v, ok = %v", b, ok)
+	}
+
+	if _, ok := r.ByOrigin("https://unknown.example.com"); ok {
+		t.Errorf("ByOrigin(unknown) should not match")
+	}
+}
+
+func TestRecipientAllowed(t *testing.T) {
+	open := Config{EmailTo: "owner@example.com"}
+	if !open.RecipientAllowed("anyone@example.com") {
+		t.Error("RecipientAllowed() with no AllowedRecipients should allow any address")
+	}
+
+	restricted := Config{EmailTo: "owner@example.com", AllowedRecipients: []string{"owner@example.com", "escalation@example.com"}}
+	if !restricted.RecipientAllowed("owner@example.com") {
+		t.Error("RecipientAllowed() should allow a listed address")
+	}
+	if restricted.RecipientAllowed("attacker@example.com") {
+		t.Error("RecipientAllowed() should reject an unlisted address")
+	}
+}
+
+func TestFromEnvFallsBackToSingleTenant(t *testing.T) {
+	t.Setenv("TENANTS_FILE", "")
+	t.Setenv("SITE", "example.com")
+	t.Setenv("EMAIL_TO", "owner@example.com")
+
+	r := FromEnv()
+	c, ok := r.ByOrigin("https://example.com")
+	if !ok || c.EmailTo != "owner@example.com" {
+		t.Errorf("ByOrigin(default) = %+v, ok = %v", c, ok)
+	}
+}
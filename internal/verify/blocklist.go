@@ -0,0 +1,38 @@
+package verify
+
+import (
+	"os"
+	"strings"
+)
+
+// LoadBlocklist reads one domain per line from path (blank lines and lines
+// starting with # are ignored) and returns a lowercase lookup set.
+func LoadBlocklist(path string) (map[string]bool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.ToLower(line)] = true
+	}
+	return set, nil
+}
+
+// BlocklistFromEnv loads the disposable-domain blocklist named by
+// DISPOSABLE_DOMAINS_FILE, if set. A missing or unreadable file yields an
+// empty blocklist rather than failing submissions outright.
+func BlocklistFromEnv() map[string]bool {
+	set, err := LoadBlocklist(os.Getenv("DISPOSABLE_DOMAINS_FILE"))
+	if err != nil || set == nil {
+		return map[string]bool{}
+	}
+	return set
+}
@@ -0,0 +1,180 @@
+/*
+Package verify validates email addresses beyond naive syntax checks,
+layering syntax, MX and optional SMTP mailbox probes.
+*/
+package verify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+// Depth controls how much verification is performed before an address is
+// accepted.
+type Depth int
+
+const (
+	// DepthSyntax only checks RFC 5321-ish syntax.
+	DepthSyntax Depth = iota
+	// DepthMX additionally requires the domain to have MX records.
+	DepthMX
+	// DepthSMTP additionally probes the mailbox over SMTP.
+	DepthSMTP
+)
+
+// DepthFromEnv reads EMAIL_VERIFY_DEPTH ("syntax", "mx" or "smtp") and
+// defaults to DepthMX, matching the historical net.LookupMX behavior.
+func DepthFromEnv() Depth {
+	switch strings.ToLower(os.Getenv("EMAIL_VERIFY_DEPTH")) {
+	case "syntax":
+		return DepthSyntax
+	case "smtp":
+		return DepthSMTP
+	default:
+		return DepthMX
+	}
+}
+
+// Error codes returned by Email, distinguishing why an address was rejected.
+var (
+	ErrInvalidSyntax   = errors.New("invalid_syntax")
+	ErrNoMX            = errors.New("no_mx")
+	ErrMailboxRejected = errors.New("mailbox_rejected")
+	ErrDisposable      = errors.New("disposable")
+)
+
+var (
+	localPartRegex = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+$")
+	hostRegex      = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+)
+
+// Email verifies addr to the given depth, rejecting any domain present in
+// blocklist outright. It returns one of the Err* sentinels on failure.
+func Email(ctx context.Context, addr string, depth Depth, blocklist map[string]bool) error {
+	domain, ok := splitDomain(addr)
+	if !ok {
+		return ErrInvalidSyntax
+	}
+	if blocklist[strings.ToLower(domain)] {
+		return ErrDisposable
+	}
+	if depth == DepthSyntax {
+		return nil
+	}
+
+	mx, err := lookupMX(ctx, domain)
+	if err != nil || len(mx) == 0 {
+		return ErrNoMX
+	}
+	if depth == DepthMX {
+		return nil
+	}
+
+	return probeMailbox(mx[0].Host, addr)
+}
+
+// splitDomain validates addr's local-part and host syntax and returns the
+// (IDN-normalized) domain.
+func splitDomain(addr string) (string, bool) {
+	at := strings.LastIndex(addr, "@")
+	if at <= 0 || at == len(addr)-1 {
+		return "", false
+	}
+	local, host := addr[:at], addr[at+1:]
+	if !localPartRegex.MatchString(local) {
+		return "", false
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil || !hostRegex.MatchString(ascii) {
+		return "", false
+	}
+	return ascii, true
+}
+
+type mxCacheEntry struct {
+	records []*net.MX
+	expires time.Time
+}
+
+const mxCacheTTL = 10 * time.Minute
+
+var (
+	mxCacheMu sync.Mutex
+	mxCache   = map[string]mxCacheEntry{}
+)
+
+// lookupMX resolves domain's MX records under a bounded timeout, caching
+// successful lookups for mxCacheTTL to keep repeat submissions cheap.
+func lookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	mxCacheMu.Lock()
+	entry, cached := mxCache[domain]
+	mxCacheMu.Unlock()
+	if cached && time.Now().Before(entry.expires) {
+		return entry.records, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	mx, err := net.DefaultResolver.LookupMX(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	mxCacheMu.Lock()
+	mxCache[domain] = mxCacheEntry{records: mx, expires: time.Now().Add(mxCacheTTL)}
+	mxCacheMu.Unlock()
+	return mx, nil
+}
+
+// probeDomain is the sender address used in the MAIL FROM of a mailbox
+// probe, configurable since receiving servers may greylist unknown senders.
+func probeDomain() string {
+	if d := os.Getenv("SMTP_PROBE_DOMAIN"); d != "" {
+		return d
+	}
+	return "example.com"
+}
+
+// probeMailbox connects to mxHost and issues HELO/MAIL FROM/RCPT TO to see
+// whether addr is accepted, without sending an actual message. Only a
+// permanent (5xx) reply to RCPT TO is treated as a rejection; network
+// failures and temporary (4xx, e.g. greylisting) replies are not, since
+// they say nothing about the mailbox itself.
+func probeMailbox(mxHost, addr string) error {
+	conn, err := net.DialTimeout("tcp", strings.TrimSuffix(mxHost, ".")+":25", 5*time.Second)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	client, err := smtp.NewClient(conn, mxHost)
+	if err != nil {
+		return nil
+	}
+	defer client.Close()
+
+	if err := client.Hello("verify.local"); err != nil {
+		return nil
+	}
+	if err := client.Mail("probe@" + probeDomain()); err != nil {
+		return nil
+	}
+	if err := client.Rcpt(addr); err != nil {
+		var reply *textproto.Error
+		if errors.As(err, &reply) && reply.Code >= 500 {
+			return ErrMailboxRejected
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package verify
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEmailSyntax(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr error
+	}{
+		{"alice@example.com", nil},
+		{"alice.bob+tag@example.co.uk", nil},
+		{"not-an-email", ErrInvalidSyntax},
+		{"@example.com", ErrInvalidSyntax},
+		{"alice@", ErrInvalidSyntax},
+		{"alice@ex ample.com", ErrInvalidSyntax},
+	}
+	for _, tt := range tests {
+		err := Email(context.Background(), tt.addr, DepthSyntax, nil)
+		if err != tt.wantErr {
+			t.Errorf("Email(%q, DepthSyntax) = %v, want %v", tt.addr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEmailDisposableBlocklist(t *testing.T) {
+	blocklist := map[string]bool{"mailinator.com": true}
+	err := Email(context.Background(), "alice@mailinator.com", DepthSyntax, blocklist)
+	if err != ErrDisposable {
+		t.Errorf("Email() = %v, want %v", err, ErrDisposable)
+	}
+}
+
+func TestDepthFromEnv(t *testing.T) {
+	tests := map[string]Depth{
+		"syntax": DepthSyntax,
+		"mx":     DepthMX,
+		"smtp":   DepthSMTP,
+		"":       DepthMX,
+		"bogus":  DepthMX,
+	}
+	for raw, want := range tests {
+		t.Setenv("EMAIL_VERIFY_DEPTH", raw)
+		if got := DepthFromEnv(); got != want {
+			t.Errorf("DepthFromEnv() with EMAIL_VERIFY_DEPTH=%q = %v, want %v", raw, got, want)
+		}
+	}
+}
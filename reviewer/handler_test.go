@@ -0,0 +1,112 @@
+package reviewer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/presence-web-services/reviewer/internal/mail"
+	"github.com/presence-web-services/reviewer/internal/tenant"
+	"github.com/presence-web-services/reviewer/internal/verify"
+)
+
+// testRegistry returns a single-tenant registry allowing origin.
+func testRegistry(origin string) *tenant.Registry {
+	return tenant.NewRegistry([]tenant.Config{{ID: "default", AllowedOrigin: origin}})
+}
+
+func TestHandlerValidPostSendsEmail(t *testing.T) {
+	a.Tenants = testRegistry("https://example.com")
+	a.VerifyDepth = verify.DepthSyntax
+	mock := &mail.MockMailer{}
+	a.Mailer = mock
+
+	form := url.Values{
+		"email":  {"alice@example.com"},
+		"name":   {"Alice"},
+		"stars":  {"5"},
+		"review": {"Loved it!"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://example.com")
+	rec := httptest.NewRecorder()
+
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	sent := mock.Messages()
+	if len(sent) != 1 {
+		t.Fatalf("got %d sent messages, want 1", len(sent))
+	}
+	if sent[0].ReplyTo != "alice@example.com" {
+		t.Errorf("ReplyTo = %q, want %q", sent[0].ReplyTo, "alice@example.com")
+	}
+	want := "Name: Alice\nStars: 5\nReview: Loved it!"
+	if sent[0].Body != want {
+		t.Errorf("Body = %q, want %q", sent[0].Body, want)
+	}
+}
+
+// TestHandlerSendsEmailWithUnconfiguredEmailTo guards against regressing
+// emailTo/sendEmail into treating a tenant that simply never configured
+// EmailTo as if it were blocked by AllowedRecipients: testRegistry's tenant
+// has no EmailTo and no AllowedRecipients, and the submission must still
+// succeed.
+func TestHandlerSendsEmailWithUnconfiguredEmailTo(t *testing.T) {
+	a.Tenants = testRegistry("https://example.com")
+	a.VerifyDepth = verify.DepthSyntax
+	mock := &mail.MockMailer{}
+	a.Mailer = mock
+
+	form := url.Values{
+		"email":  {"alice@example.com"},
+		"name":   {"Alice"},
+		"stars":  {"5"},
+		"review": {"Loved it!"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://example.com")
+	rec := httptest.NewRecorder()
+
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if len(mock.Messages()) != 1 {
+		t.Fatalf("got %d sent messages, want 1", len(mock.Messages()))
+	}
+}
+
+func TestHandlerInvalidPostReturns4xx(t *testing.T) {
+	a.Tenants = testRegistry("https://example.com")
+	a.VerifyDepth = verify.DepthSyntax
+	mock := &mail.MockMailer{}
+	a.Mailer = mock
+
+	form := url.Values{
+		"email":  {"not-an-email"},
+		"name":   {"Alice"},
+		"stars":  {"5"},
+		"review": {"Loved it!"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://example.com")
+	rec := httptest.NewRecorder()
+
+	handler(rec, req)
+
+	if rec.Code < 400 || rec.Code > 499 {
+		t.Fatalf("status = %d, want 4xx; body = %s", rec.Code, rec.Body.String())
+	}
+	if len(mock.Messages()) != 0 {
+		t.Errorf("invalid submission should not have sent an email")
+	}
+}
@@ -4,221 +4,350 @@ Reviewer sets up a server that listens for POST data (on specified port) and sen
 package reviewer
 
 import (
+	"context"
+	"errors"
 	"log"
-	"net"
 	"net/http"
-	"os"
-	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
+	"unicode"
 
-	"github.com/presence-web-services/gmailer/v2"
-	"github.com/joho/godotenv"
+	"github.com/presence-web-services/reviewer/internal/app"
+	"github.com/presence-web-services/reviewer/internal/mail"
+	"github.com/presence-web-services/reviewer/internal/store"
+	"github.com/presence-web-services/reviewer/internal/tenant"
 )
 
-// gmailer config for sending email
-var config gmailer.Config
+// errNotAllowedRecipient is returned by retrySubmission when a submission's
+// resolved recipient is not in its tenant's AllowedRecipients, so the retry
+// worker records the submission as failed and backs off instead of sending
+// it.
+var errNotAllowedRecipient = errors.New("recipient not in tenant's allowed_recipients")
 
-// default important values
-var status = http.StatusOK
-var errorMessage = ""
-var hp = ""
-var site = ""
-var name = ""
-var stars = ""
-var review = ""
+// a holds the mailer, tenant registry, anti-spam/email-verification config
+// and submission store shared across requests.
+var a = app.New()
 
-// init loads environment variables and authenticates the gmailer config
+// init loads environment variables and authenticates the default mailer
 func init() {
-	loadEnvVars()
+	a.LoadEnvVars()
 	authenticate()
 }
 
-// CreateAndRun is exported to allow for creation of a reviewer
-func CreateAndRun(port string) {
+// CreateAndRun is exported to allow for creation of a reviewer. If m is
+// non-nil, it replaces the mailer built from the environment.
+func CreateAndRun(port string, m mail.Mailer) {
+	if m != nil {
+		a.Mailer = m
+	}
 	http.HandleFunc("/", handler)
+	http.HandleFunc("/submissions", a.SubmissionsHandler)
 	http.ListenAndServe(":"+port, nil)
 }
 
-// loadEnvVars loads environment variables from a .env file
-func loadEnvVars() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error: Could not load environment variables from .env file.")
-	}
-	config.ClientID = os.Getenv("CLIENT_ID")
-	config.ClientSecret = os.Getenv("CLIENT_SECRET")
-	config.AccessToken = os.Getenv("ACCESS_TOKEN")
-	config.RefreshToken = os.Getenv("REFRESH_TOKEN")
-	config.EmailTo = os.Getenv("EMAIL_TO")
-	config.EmailFrom = os.Getenv("EMAIL_FROM")
-	config.Subject = os.Getenv("SUBJECT")
-	site = "https://" + os.Getenv("SITE")
-}
-
-// authenticate authenticates a gmailer config
+// authenticate authenticates the default mailer
 func authenticate() {
-	err := config.Authenticate()
-	if err != nil {
-		log.Fatal("Error: Could not authenticate with GMail OAuth using credentials.")
+	if err := a.Authenticate(); err != nil {
+		log.Println("Warning: Could not authenticate mailer using configured credentials.")
 	}
 }
 
-// sendEmail sends an email given a gmailer config
-func sendEmail() {
-	err := config.Send()
-	if err != nil {
-		status = http.StatusInternalServerError
-		errorMessage = "Error: Internal server error."
-		return
-	}
+// submission holds all state for a single in-flight POST. Every handler
+// invocation gets its own submission, so concurrent requests can never
+// clobber each other's fields.
+type submission struct {
+	status  int
+	errMsg  string
+	tenant  tenant.Config
+	replyTo string
+	body    string
+	name    string
+	stars   string
+	review  string
+	hp      string
+
+	// storeID is the persisted row ID for this submission, or 0 if it was
+	// never saved (no Store configured, or the save itself failed).
+	storeID int64
 }
 
-// defaultValues sets the status, errorMessage, ReplyTo, Body all to default values
-func defaultValues() {
-	status = http.StatusOK
-	errorMessage = ""
-	hp = ""
-  name = ""
-  stars = ""
-  review = ""
-	config.ReplyTo = ""
-	config.Body = ""
+// newSubmission returns a submission primed with the default status.
+func newSubmission() *submission {
+	return &submission{status: http.StatusOK}
 }
 
 // handler verifies a POST is sent, and then validates the POST data, and sends an email if valid
 func handler(response http.ResponseWriter, request *http.Request) {
-	defaultValues()
-	response.Header().Set("Access-Control-Allow-Origin", site)
-	checkOrigin(request)
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s := newSubmission()
+	s.resolveTenant(request)
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
+		return
+	}
+	response.Header().Set("Access-Control-Allow-Origin", s.tenant.AllowedOrigin)
+	s.verifyPost(response, request.Method)
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	verifyPost(response, request.Method)
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.getFormData(request)
+	s.checkSpam(request)
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	getFormData(request)
-  checkName()
-  if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.checkName()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	checkEmail()
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.checkEmail()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-  checkRating()
-  if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.checkRating()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	checkReview()
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.checkReview()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-	checkHP()
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.checkHP()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
-  createBody()
-	sendEmail()
-	if status != http.StatusOK {
-		http.Error(response, errorMessage, status)
+	s.createBody()
+	s.saveSubmission(request)
+	s.sendEmail()
+	s.recordEmailOutcome()
+	if s.status != http.StatusOK {
+		http.Error(response, s.errMsg, s.status)
 		return
 	}
 	response.Write([]byte("Email sent successfully!"))
 }
 
-// checkOrigin ensures origin is from proper website
-func checkOrigin(request *http.Request) {
-	origin := request.Header.Get("Origin")
-	if origin != site {
-		status = http.StatusForbidden
-		errorMessage = "Error: Only certain sites are allowed to use this endpoint."
+// resolveTenant looks up the tenant for request, so one deployment can
+// serve several sites.
+func (s *submission) resolveTenant(request *http.Request) {
+	t, ok := a.ResolveTenant(request)
+	if !ok {
+		s.status = http.StatusForbidden
+		s.errMsg = "Error: Only certain sites are allowed to use this endpoint."
 		return
 	}
+	s.tenant = t
 }
 
 // verifyPost ensures that a POST is sent
-func verifyPost(response http.ResponseWriter, method string) {
-	if method != "POST" {
-		response.Header().Set("Allow", "POST")
-		status = http.StatusMethodNotAllowed
-		errorMessage = "Error: Method " + method + " not allowed. Only POST allowed."
+func (s *submission) verifyPost(response http.ResponseWriter, method string) {
+	if status, errMsg := app.VerifyPost(response, method); status != 0 {
+		s.status, s.errMsg = status, errMsg
 	}
 }
 
-// getFormData populates config struct and hp variable with POSTed data from form submission
-func getFormData(request *http.Request) {
-	config.ReplyTo = request.PostFormValue("email")
-	name = request.PostFormValue("name")
-  stars = request.PostFormValue("stars")
-  review = request.PostFormValue("review")
-	hp = request.PostFormValue("hp")
+// getFormData populates the submission with POSTed data from the form
+func (s *submission) getFormData(request *http.Request) {
+	s.replyTo = request.PostFormValue("email")
+	s.name = request.PostFormValue("name")
+	s.stars = request.PostFormValue("stars")
+	s.review = request.PostFormValue("review")
+	s.hp = request.PostFormValue(s.tenant.HoneypotFieldName)
 }
 
-// checkName verifies name is valid length
-func checkName() {
-  if len(name) == 0 || len(name) > 100 {
-    status = http.StatusBadRequest
-		errorMessage = "Error: Name is blank or too long."
+// checkSpam runs the tenant's anti-spam pipeline, if enabled: per-IP and
+// per-email rate limiting, CAPTCHA verification, then content heuristics.
+func (s *submission) checkSpam(request *http.Request) {
+	if status, errMsg := a.CheckSpam(request, s.tenant, s.replyTo, s.review); status != 0 {
+		s.status, s.errMsg = status, errMsg
+	}
+}
+
+// checkName verifies name is valid length and contains no control
+// characters (CR/LF could otherwise inject extra headers into the
+// outgoing email, since name ends up in the templated Subject).
+func (s *submission) checkName() {
+	if len(s.name) == 0 || len(s.name) > 100 || containsControlChars(s.name) {
+		s.status = http.StatusBadRequest
+		s.errMsg = "Error: Name is blank, too long, or contains invalid characters."
 		return
-  }
+	}
 }
 
-func checkStars() {
-  stars_int, err := strconv.Atoi(stars)
-  if err || stars_int < 1 || stars_int > 5 {
-    status = http.StatusBadRequest
-		errorMessage = "Error: Star rating must be between 1-5."
+// checkRating verifies star rating is between 1 and 5
+func (s *submission) checkRating() {
+	starsInt, err := strconv.Atoi(s.stars)
+	if err != nil || starsInt < 1 || starsInt > 5 {
+		s.status = http.StatusBadRequest
+		s.errMsg = "Error: Star rating must be between 1-5."
 		return
-  }
+	}
 }
 
 // checkEmail verifies email submitted is valid
-func checkEmail() {
-	if len(config.ReplyTo) < 5 || len(config.ReplyTo) > 50 {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Email is too short or too long."
+func (s *submission) checkEmail() {
+	if status, errMsg := a.CheckEmail(s.replyTo); status != 0 {
+		s.status, s.errMsg = status, errMsg
+	}
+}
+
+// checkReview verifies message submitted is valid length and contains no
+// control characters (see checkName).
+func (s *submission) checkReview() {
+	if len(s.review) == 0 || len(s.review) > 2000 || containsControlChars(s.review) {
+		s.status = http.StatusBadRequest
+		s.errMsg = "Error: Review is too long, empty, or contains invalid characters."
 		return
 	}
-	var emailRegex = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
-	if !emailRegex.MatchString(config.ReplyTo) {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Email is not a valid format."
+}
+
+// checkHP ensures honeypot field is not populated
+func (s *submission) checkHP() {
+	if status, errMsg := app.CheckHP(s.hp); status != 0 {
+		s.status, s.errMsg = status, errMsg
+	}
+}
+
+// createBody renders the email body from the submitted fields
+func (s *submission) createBody() {
+	s.body = "Name: " + s.name + "\nStars: " + s.stars + "\nReview: " + s.review
+}
+
+// sendEmail sends the submission through the configured mailer, routed to
+// this submission's tenant
+func (s *submission) sendEmail() {
+	to := emailTo(s.tenant, s.stars)
+	if !s.tenant.RecipientAllowed(to) {
+		s.status = http.StatusInternalServerError
+		s.errMsg = "Error: Internal server error."
 		return
 	}
-	domain := strings.Split(config.ReplyTo, "@")[1]
-	mx, err := net.LookupMX(domain)
-	if err != nil || len(mx) == 0 {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Domain given is not a valid email domain."
+	err := a.Mailer.Send(context.Background(), mail.Message{
+		ReplyTo: s.replyTo,
+		Body:    s.body,
+		To:      to,
+		From:    s.tenant.EmailFrom,
+		Subject: renderSubject(s.tenant, s.name, s.stars, s.review),
+	})
+	if err != nil {
+		s.status = http.StatusInternalServerError
+		s.errMsg = "Error: Internal server error."
 		return
 	}
 }
 
-// checkReview verifies message submitted is valid
-func checkReview() {
-	if len(review) == 0 || len(review) > 2000 {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Review is too long or empty."
-		return
+// emailTo returns the address a submission with the given star rating
+// should be sent to: t's escalation address for ratings of 2 stars or
+// lower, if one is configured, otherwise t's normal EmailTo. Callers must
+// separately check t.RecipientAllowed(result) before sending, so that an
+// address blocked by AllowedRecipients can't be confused with an address
+// that was simply never configured.
+func emailTo(t tenant.Config, stars string) string {
+	if n, err := strconv.Atoi(stars); err == nil && n <= 2 && t.EscalationEmailTo != "" {
+		return t.EscalationEmailTo
 	}
+	return t.EmailTo
 }
 
-// checkHP ensures honeypot field is not populated
-func checkHP() {
-	if hp != "" {
-		status = http.StatusBadRequest
-		errorMessage = "Error: Please, no robots!"
+// renderSubject returns the email subject for a submission with the given
+// name, star rating and review: t's SubjectTemplate rendered with those
+// fields if one is configured, otherwise t's plain Subject. Template
+// parse or execution errors fall back to the plain Subject. Fields are
+// stripped of control characters before rendering as defense in depth:
+// checkName/checkReview should already have rejected them, but a raw CR/LF
+// reaching a Subject header would let a submission inject extra headers
+// (e.g. Bcc) into the outgoing email.
+func renderSubject(t tenant.Config, name, stars, review string) string {
+	if t.SubjectTemplate == "" {
+		return t.Subject
+	}
+	tmpl, err := template.New("subject").Parse(t.SubjectTemplate)
+	if err != nil {
+		log.Println("Warning: Could not parse subject template, falling back to plain subject:", err)
+		return t.Subject
+	}
+	var buf strings.Builder
+	data := struct{ Name, Stars, Review string }{
+		stripControlChars(name), stripControlChars(stars), stripControlChars(review),
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Println("Warning: Could not render subject template, falling back to plain subject:", err)
+		return t.Subject
+	}
+	return stripControlChars(buf.String())
+}
+
+// containsControlChars reports whether s contains CR, LF, or any other
+// control character (tab excepted), which could otherwise be used to
+// inject extra header lines into an outgoing email.
+func containsControlChars(s string) bool {
+	for _, r := range s {
+		if r != '\t' && unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripControlChars removes every control character (tab excepted) from s.
+func stripControlChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// saveSubmission persists the submission before sendEmail is attempted, so
+// a failed send does not lose it. It is a no-op if no Store is configured.
+func (s *submission) saveSubmission(request *http.Request) {
+	sub := &store.Submission{
+		Tenant:      s.tenant.ID,
+		ReceivedAt:  time.Now(),
+		RemoteIP:    app.ClientIP(request),
+		Name:        s.name,
+		Email:       s.replyTo,
+		Stars:       s.stars,
+		Message:     s.review,
+		EmailStatus: store.StatusPending,
 	}
+	s.storeID = a.SaveSubmission(sub)
+}
+
+// recordEmailOutcome updates the persisted submission, if any, with the
+// result of sendEmail.
+func (s *submission) recordEmailOutcome() {
+	a.RecordEmailOutcome(s.storeID, s.status, s.errMsg)
 }
 
-func createBody() {
-  config.Body = fmt.Sprintf("Name: %s\nStars: %s\nReview: %s", name, stars, review)
+// StartRetryWorker periodically resends submissions whose email failed,
+// backing off exponentially per submission so a down mail server isn't
+// hammered. It returns a function that stops the worker.
+func StartRetryWorker(interval time.Duration) (stop func()) {
+	return a.StartRetryWorker(interval, retrySubmission)
+}
+
+// retrySubmission re-sends a single persisted submission, addressed and
+// worded the same way a fresh submission would be.
+func retrySubmission(ctx context.Context, sub store.Submission, t tenant.Config) error {
+	to := emailTo(t, sub.Stars)
+	if !t.RecipientAllowed(to) {
+		return errNotAllowedRecipient
+	}
+	body := "Name: " + sub.Name + "\nStars: " + sub.Stars + "\nReview: " + sub.Message
+	return a.Mailer.Send(ctx, mail.Message{
+		ReplyTo: sub.Email,
+		Body:    body,
+		To:      to,
+		From:    t.EmailFrom,
+		Subject: renderSubject(t, sub.Name, sub.Stars, sub.Message),
+	})
 }
@@ -0,0 +1,201 @@
+package reviewer
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/presence-web-services/reviewer/internal/tenant"
+)
+
+// TestConcurrentSubmissionsDoNotCrossTalk runs many submissions with
+// distinct payloads through the validation pipeline in parallel and
+// checks that each one only ever sees its own data.
+func TestConcurrentSubmissionsDoNotCrossTalk(t *testing.T) {
+	cases := []struct {
+		name   string
+		stars  string
+		review string
+	}{
+		{"Alice", "5", "Loved it!"},
+		{"Bob", "4", "Pretty good."},
+		{"Carol", "1", "Not great."},
+		{"Dave", "3", "It was fine."},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for _, c := range cases {
+			c := c
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				s := newSubmission()
+				s.name = c.name
+				s.stars = c.stars
+				s.review = c.review
+
+				s.checkName()
+				s.checkRating()
+				s.checkReview()
+				s.createBody()
+
+				if s.status != 200 {
+					t.Errorf("unexpected status %d for %s", s.status, c.name)
+				}
+				want := "Name: " + c.name + "\nStars: " + c.stars + "\nReview: " + c.review
+				if s.body != want {
+					t.Errorf("cross-talk detected: got body %q, want %q", s.body, want)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestCheckName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"", true},
+		{"Alice", false},
+		{string(make([]byte, 101)), true},
+		{"Eve\r\nBcc: attacker@evil.com", true},
+		{"Eve\nBcc: attacker@evil.com", true},
+	}
+	for _, tt := range tests {
+		s := newSubmission()
+		s.name = tt.name
+		s.checkName()
+		gotErr := s.status != 200
+		if gotErr != tt.wantErr {
+			t.Errorf("checkName(%q) error = %v, want %v", tt.name, gotErr, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckRating(t *testing.T) {
+	tests := []struct {
+		stars   string
+		wantErr bool
+	}{
+		{"0", true},
+		{"1", false},
+		{"5", false},
+		{"6", true},
+		{"abc", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		s := newSubmission()
+		s.stars = tt.stars
+		s.checkRating()
+		gotErr := s.status != 200
+		if gotErr != tt.wantErr {
+			t.Errorf("checkRating(%q) error = %v, want %v", tt.stars, gotErr, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckReview(t *testing.T) {
+	tests := []struct {
+		review  string
+		wantErr bool
+	}{
+		{"", true},
+		{"Great service!", false},
+		{string(make([]byte, 2001)), true},
+		{"Great\r\nBcc: attacker@evil.com", true},
+	}
+	for _, tt := range tests {
+		s := newSubmission()
+		s.review = tt.review
+		s.checkReview()
+		gotErr := s.status != 200
+		if gotErr != tt.wantErr {
+			t.Errorf("checkReview(%q) error = %v, want %v", tt.review, gotErr, tt.wantErr)
+		}
+	}
+}
+
+func TestEmailToRespectsAllowedRecipients(t *testing.T) {
+	c := tenant.Config{
+		EmailTo:           "owner@example.com",
+		EscalationEmailTo: "escalation@example.com",
+		AllowedRecipients: []string{"owner@example.com"},
+	}
+	if got := emailTo(c, "5"); got != "owner@example.com" || !c.RecipientAllowed(got) {
+		t.Errorf("emailTo(allowed) = %q, RecipientAllowed = %v, want %q, true", got, c.RecipientAllowed(got), "owner@example.com")
+	}
+	if got := emailTo(c, "1"); got != "escalation@example.com" || c.RecipientAllowed(got) {
+		t.Errorf("emailTo(escalation) = %q, RecipientAllowed = %v, want %q, false", got, c.RecipientAllowed(got), "escalation@example.com")
+	}
+}
+
+// TestEmailToUnconfiguredIsNotTreatedAsBlocked guards against conflating
+// "EmailTo was never set" with "EmailTo is blocked by AllowedRecipients":
+// with no AllowedRecipients configured, an empty EmailTo must still be
+// reported as allowed by RecipientAllowed, matching emailTo's contract that
+// callers check the two independently.
+func TestEmailToUnconfiguredIsNotTreatedAsBlocked(t *testing.T) {
+	c := tenant.Config{}
+	got := emailTo(c, "5")
+	if got != "" {
+		t.Errorf("emailTo(unconfigured) = %q, want \"\"", got)
+	}
+	if !c.RecipientAllowed(got) {
+		t.Error("RecipientAllowed(\"\") with no AllowedRecipients configured should be true, not treated as blocked")
+	}
+}
+
+func TestRenderSubjectStripsControlChars(t *testing.T) {
+	templated := tenant.Config{Subject: "New review", SubjectTemplate: "[{{.Stars}}★] New review from {{.Name}}"}
+	got := renderSubject(templated, "Eve\r\nBcc: attacker@evil.com", "5", "Great!")
+	if strings.ContainsAny(got, "\r\n") {
+		t.Errorf("renderSubject(malicious name) = %q, want no CR/LF", got)
+	}
+}
+
+func TestRenderSubject(t *testing.T) {
+	plain := tenant.Config{Subject: "New review"}
+	if got := renderSubject(plain, "Alice", "5", "Great!"); got != "New review" {
+		t.Errorf("renderSubject(plain) = %q, want %q", got, "New review")
+	}
+
+	templated := tenant.Config{Subject: "New review", SubjectTemplate: "[{{.Stars}}★] New review from {{.Name}}"}
+	want := "[5★] New review from Alice"
+	if got := renderSubject(templated, "Alice", "5", "Great!"); got != want {
+		t.Errorf("renderSubject(templated) = %q, want %q", got, want)
+	}
+
+	broken := tenant.Config{Subject: "New review", SubjectTemplate: "{{.Nope"}
+	if got := renderSubject(broken, "Alice", "5", "Great!"); got != "New review" {
+		t.Errorf("renderSubject(broken template) = %q, want fallback %q", got, "New review")
+	}
+}
+
+func TestEmailTo(t *testing.T) {
+	c := tenant.Config{EmailTo: "owner@example.com", EscalationEmailTo: "escalation@example.com"}
+	tests := []struct {
+		stars string
+		want  string
+	}{
+		{"1", "escalation@example.com"},
+		{"2", "escalation@example.com"},
+		{"3", "owner@example.com"},
+		{"5", "owner@example.com"},
+		{"abc", "owner@example.com"},
+	}
+	for _, tt := range tests {
+		if got := emailTo(c, tt.stars); got != tt.want {
+			t.Errorf("emailTo(stars=%q) = %q, want %q", tt.stars, got, tt.want)
+		}
+	}
+
+	noEscalation := tenant.Config{EmailTo: "owner@example.com"}
+	if got := emailTo(noEscalation, "1"); got != "owner@example.com" {
+		t.Errorf("emailTo(no escalation configured) = %q, want %q", got, "owner@example.com")
+	}
+}